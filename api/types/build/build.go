@@ -0,0 +1,39 @@
+// Package build holds the options accepted by the image build endpoint.
+package build
+
+import units "github.com/docker/go-units"
+
+// ImageBuildOptions holds the set of options that are used to configure an
+// image build.
+//
+// This only declares the fields referenced by daemon/builder/dockerfile in
+// this checkout, not the full option set of the real endpoint.
+type ImageBuildOptions struct {
+	Platform     string
+	CgroupParent string
+	CPUShares    int64
+	CPUPeriod    int64
+	CPUQuota     int64
+	CPUSetCPUs   string
+	CPUSetMems   string
+	Memory       int64
+	MemorySwap   int64
+	Ulimits      []*units.Ulimit
+	NetworkMode  string
+	SecurityOpt  []string
+	Isolation    string
+	ShmSize      int64
+	ExtraHosts   []string
+
+	// PreserveXAttrs carries over extended attributes (security.capability,
+	// user.*, POSIX ACLs) from COPY/ADD file sources onto their
+	// destinations. Directory sources are copied by the archiver, which does
+	// not consult this option.
+	PreserveXAttrs bool
+
+	// ContentAddressableCopyCache additionally probes the build cache with a
+	// key built from a hash of COPY/ADD sources' actual bytes. Steps are
+	// still committed under the path-based key only, so this probe only hits
+	// an image whose config already carries the content-addressed key.
+	ContentAddressableCopyCache bool
+}
@@ -0,0 +1,107 @@
+// Package container holds the container configuration types shared between
+// the API and the daemon.
+package container
+
+import (
+	"strings"
+
+	units "github.com/docker/go-units"
+)
+
+// This only declares the fields/methods referenced by daemon/builder/dockerfile
+// and daemon/oci_windows.go in this checkout, not the full HostConfig of the
+// real API type.
+
+// NetworkMode represents the container's network stack.
+type NetworkMode string
+
+// IsContainer indicates whether the container uses another container's
+// network stack, i.e. "container:<name-or-id>".
+func (n NetworkMode) IsContainer() bool {
+	parts := strings.SplitN(string(n), ":", 2)
+	return len(parts) > 1 && parts[0] == "container"
+}
+
+// Isolation represents the isolation technology a container runs with.
+type Isolation string
+
+// IsDefault indicates the default isolation technology of a container. On
+// Linux this is the only supported value. On Windows, this can be
+// "hyperv" or "process".
+func (i Isolation) IsDefault() bool {
+	return strings.ToLower(string(i)) == "default" || string(i) == ""
+}
+
+// IsHyperV indicates the use of a Hyper-V partition for isolation.
+func (i Isolation) IsHyperV() bool {
+	return strings.ToLower(string(i)) == "hyperv"
+}
+
+// LogConfig represents the logging configuration of a container.
+type LogConfig struct {
+	Type string
+}
+
+// DeviceMapping represents the device mapping between the host and the
+// container.
+type DeviceMapping struct {
+	PathOnHost        string
+	PathInContainer   string
+	CgroupPermissions string
+}
+
+// Resources contains the resource constraints a container is created with.
+//
+// It's embedded anonymously in HostConfig so callers can write
+// c.HostConfig.CPUShares instead of c.HostConfig.Resources.CPUShares,
+// matching the real API type.
+type Resources struct {
+	CgroupParent string
+	CPUShares    int64
+	CPUPeriod    int64
+	CPUQuota     int64
+	CPUPercent   int64
+	CPUCount     int64
+	NanoCPUs     int64
+	CpusetCpus   string
+	CpusetMems   string
+	Memory       int64
+	MemorySwap   int64
+	Ulimits      []*units.Ulimit
+
+	// IOMaximumBandwidth and IOMaximumIOps are Windows-only storage I/O
+	// limits, pointed to directly by setResourcesInSpec so they must remain
+	// addressable fields rather than returned copies.
+	IOMaximumBandwidth uint64
+	IOMaximumIOps      uint64
+}
+
+// HostConfig holds the container's host-specific configuration.
+type HostConfig struct {
+	SecurityOpt []string
+	Isolation   Isolation
+	ShmSize     int64
+	Resources
+	NetworkMode NetworkMode
+	LogConfig   LogConfig
+	ExtraHosts  []string
+	Devices     []DeviceMapping
+
+	// Annotations are passed through to the OCI spec via
+	// containerd/pkg/oci.WithAnnotations.
+	Annotations map[string]string
+
+	// ConsoleSize is the initial [height, width] of the container's console,
+	// when attached to a terminal.
+	ConsoleSize [2]uint
+
+	// DNSSearch overrides the daemon-wide default DNS search list
+	// (configStore.DNSSearch) for this container.
+	DNSSearch []string
+
+	// Servicing marks a container as a Windows servicing container: a
+	// short-lived container started against a base image update so its
+	// resulting layer can be committed back without running the workload
+	// itself. See daemon/servicing_windows.go.
+	Servicing bool
+}
@@ -13,11 +13,15 @@ const (
 	RuntimePlugin RuntimeType = "plugin"
 	// RuntimeNetworkAttachment is the network attachment runtime
 	RuntimeNetworkAttachment RuntimeType = "attachment"
+	// RuntimeWASM is the WASM based runtime
+	RuntimeWASM RuntimeType = "wasm"
 
 	// RuntimeURLContainer is the proto url for the container type
 	RuntimeURLContainer RuntimeURL = "types.docker.com/RuntimeContainer"
 	// RuntimeURLPlugin is the proto url for the plugin type
 	RuntimeURLPlugin RuntimeURL = "types.docker.com/RuntimePlugin"
+	// RuntimeURLWASM is the proto url for the WASM type
+	RuntimeURLWASM RuntimeURL = "types.docker.com/RuntimeWASM"
 )
 
 // NetworkAttachmentSpec represents the runtime spec type for network
@@ -43,3 +47,34 @@ type RuntimePrivilege struct {
 	Description string   `json:"description,omitempty"`
 	Value       []string `json:"value,omitempty"`
 }
+
+// WASMPreopen maps a host directory into the guest's preopen table under a
+// different (or the same) path.
+type WASMPreopen struct {
+	HostDir  string `json:"hostDir,omitempty"`
+	GuestDir string `json:"guestDir,omitempty"`
+}
+
+// WASMResources describes the resource limits applied to a WASM task.
+type WASMResources struct {
+	// Fuel bounds the number of wasmtime/wasmedge fuel units the module may
+	// consume before being preempted. Zero means unlimited.
+	Fuel uint64 `json:"fuel,omitempty"`
+	// MaxMemoryPages bounds the guest's linear memory, in 64KiB wasm pages.
+	// Zero means unlimited.
+	MaxMemoryPages uint32 `json:"maxMemoryPages,omitempty"`
+}
+
+// WASMSpec defines the base payload which clients can specify for creating
+// a service with the WASM runtime.
+type WASMSpec struct {
+	// Module is an OCI reference to a .wasm artifact.
+	Module string `json:"module,omitempty"`
+	// Entrypoint is the name of the export invoked as the module's entry
+	// point. Defaults to "_start" if empty.
+	Entrypoint string        `json:"entrypoint,omitempty"`
+	Args       []string      `json:"args,omitempty"`
+	Env        []string      `json:"env,omitempty"`
+	Preopens   []WASMPreopen `json:"preopens,omitempty"`
+	Resources  WASMResources `json:"resources,omitempty"`
+}
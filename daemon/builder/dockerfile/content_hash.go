@@ -0,0 +1,109 @@
+package dockerfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// contentAddressedSourceHash computes a merkle hash over the source
+// subtree(s) referenced by infos, for use as an alternate cache-probe key
+// that's stable across context paths.
+func contentAddressedSourceHash(infos []copyInfo) (string, error) {
+	var hashes []string
+	for _, info := range infos {
+		h, err := merkleHash(filepath.Join(info.root, info.path))
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, h)
+	}
+	if len(hashes) == 1 {
+		return hashes[0], nil
+	}
+	return hashStringSlice("multi-ca", hashes), nil
+}
+
+// merkleHash computes a stable hash for path: a sha256 over sorted
+// "name\0mode\0uid\0gid\0size\0contenthash\n" records, recursing into
+// directories. Symlinks record their target rather than being followed, so
+// a retargeted symlink changes the hash even though readlink(2) on the
+// link itself doesn't touch file content. Within a directory, each entry's
+// line carries its name, mode and owner plus the entry's own hash; the
+// directory's own name, mode and owner are not part of its hash.
+func merkleHash(path string) (string, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	return merkleHashEntry(path, fi)
+}
+
+func merkleHashEntry(path string, fi os.FileInfo) (string, error) {
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("reading symlink %s: %w", path, err)
+		}
+		return merkleRecordHash(fi, 0, target), nil
+	case fi.IsDir():
+		return merkleHashDir(path, fi)
+	default:
+		contentHash, err := fileContentHash(path)
+		if err != nil {
+			return "", fmt.Errorf("hashing %s: %w", path, err)
+		}
+		return merkleRecordHash(fi, fi.Size(), contentHash), nil
+	}
+}
+
+func merkleHashDir(path string, fi os.FileInfo) (string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("reading directory %s: %w", path, err)
+	}
+	// Stable ordering: ReadDir already sorts by name, but don't depend on
+	// that remaining true.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	hasher := sha256.New()
+	for _, entry := range entries {
+		childInfo, err := entry.Info()
+		if err != nil {
+			return "", fmt.Errorf("stat %s: %w", filepath.Join(path, entry.Name()), err)
+		}
+		childHash, err := merkleHashEntry(filepath.Join(path, entry.Name()), childInfo)
+		if err != nil {
+			return "", err
+		}
+		uid, gid := fileOwner(childInfo)
+		fmt.Fprintf(hasher, "%s\x00%o\x00%d\x00%d\x00%s\n", entry.Name(), childInfo.Mode().Perm(), uid, gid, childHash)
+	}
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func merkleRecordHash(fi os.FileInfo, size int64, contentHash string) string {
+	uid, gid := fileOwner(fi)
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%s\x00%o\x00%d\x00%d\x00%d\x00%s\n", fi.Name(), fi.Mode().Perm(), uid, gid, size, contentHash)
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+}
+
+func fileContentHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
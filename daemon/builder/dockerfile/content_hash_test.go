@@ -0,0 +1,96 @@
+package dockerfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerkleHashStableAcrossEquivalentTrees(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+
+	for _, dir := range []string{dirA, dirB} {
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+			t.Fatalf("Mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	hashA, err := merkleHash(dirA)
+	if err != nil {
+		t.Fatalf("merkleHash(dirA): %v", err)
+	}
+	hashB, err := merkleHash(dirB)
+	if err != nil {
+		t.Fatalf("merkleHash(dirB): %v", err)
+	}
+	if hashA != hashB {
+		t.Errorf("merkleHash differs for identical trees at different paths: %s vs %s", hashA, hashB)
+	}
+}
+
+func TestMerkleHashChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	before, err := merkleHash(dir)
+	if err != nil {
+		t.Fatalf("merkleHash: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("goodbye"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	after, err := merkleHash(dir)
+	if err != nil {
+		t.Fatalf("merkleHash: %v", err)
+	}
+
+	if before == after {
+		t.Errorf("merkleHash did not change after file content changed")
+	}
+}
+
+func TestMerkleHashEntrySymlinkUsesTargetNotContent(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("content"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("target.txt", link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	linkHash, err := merkleHash(link)
+	if err != nil {
+		t.Fatalf("merkleHash(link): %v", err)
+	}
+
+	// Same link name in another directory, so only the target differs.
+	otherDir := filepath.Join(dir, "sub")
+	if err := os.Mkdir(otherDir, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	other := filepath.Join(otherDir, "link")
+	if err := os.Symlink("elsewhere.txt", other); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	otherHash, err := merkleHash(other)
+	if err != nil {
+		t.Fatalf("merkleHash(other): %v", err)
+	}
+
+	if linkHash == otherHash {
+		t.Errorf("merkleHash did not change when symlink target changed")
+	}
+}
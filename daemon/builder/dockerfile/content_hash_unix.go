@@ -0,0 +1,18 @@
+//go:build unix
+
+package dockerfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid/gid recorded in fi, or 0, 0 if the underlying
+// os.FileInfo doesn't expose a *syscall.Stat_t (e.g. a synthetic FileInfo).
+func fileOwner(fi os.FileInfo) (uint32, uint32) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return st.Uid, st.Gid
+}
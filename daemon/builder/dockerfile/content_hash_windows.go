@@ -0,0 +1,8 @@
+package dockerfile
+
+import "os"
+
+// fileOwner is a no-op on Windows, which doesn't expose POSIX uid/gid.
+func fileOwner(fi os.FileInfo) (uint32, uint32) {
+	return 0, 0
+}
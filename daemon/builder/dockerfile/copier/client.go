@@ -0,0 +1,152 @@
+package copier
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+)
+
+// Client talks the copier protocol to a chroot helper over a Unix socket.
+type Client struct {
+	conn net.Conn
+}
+
+// NewClient wraps an already-connected socket to a chroot helper.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// LocalFile describes one source file on the (non-chroot'ed) client side of
+// a Put exchange: HostPath is where the client reads bytes from, DestPath is
+// where the server should write them, relative to its own chroot.
+type LocalFile struct {
+	HostPath string
+	DestPath string
+	IsDir    bool
+	Mode     os.FileMode
+}
+
+// Put streams files onto the helper, returning one Entry per regular file
+// (with content digest) or symlink (with its target) written.
+func (c *Client) Put(req PutReq, files []LocalFile) ([]Entry, error) {
+	if err := WriteMessage(c.conn, Request{Op: OpPut, Put: &req}); err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if err := c.sendFile(f, req.PreserveXAttrs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := WriteMessage(c.conn, EntryHeader{Done: true}); err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err := ReadMessage(c.conn, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf("copier: %s", resp.Err)
+	}
+	return resp.Entries, nil
+}
+
+func (c *Client) sendFile(f LocalFile, preserveXAttrs bool) error {
+	if f.IsDir {
+		return WriteMessage(c.conn, EntryHeader{Path: f.DestPath, IsDir: true, Mode: uint32(f.Mode)})
+	}
+
+	// Lstat, not Stat: a symlink source must be recreated as a symlink on
+	// the server side, not silently dereferenced into a copy of its target's
+	// contents.
+	lfi, err := os.Lstat(f.HostPath)
+	if err != nil {
+		return fmt.Errorf("copier: stat %s: %w", f.HostPath, err)
+	}
+	if lfi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(f.HostPath)
+		if err != nil {
+			return fmt.Errorf("copier: reading symlink %s: %w", f.HostPath, err)
+		}
+		return WriteMessage(c.conn, EntryHeader{
+			Path:       f.DestPath,
+			IsSymlink:  true,
+			Mode:       uint32(lfi.Mode()),
+			LinkTarget: target,
+		})
+	}
+
+	in, err := os.Open(f.HostPath)
+	if err != nil {
+		return fmt.Errorf("copier: opening %s: %w", f.HostPath, err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("copier: stat %s: %w", f.HostPath, err)
+	}
+
+	mode := f.Mode
+	if mode == 0 {
+		mode = fi.Mode()
+	}
+
+	var xattrs []XAttr
+	if preserveXAttrs {
+		xattrs, err = readXAttrs(f.HostPath)
+		if err != nil {
+			return fmt.Errorf("copier: reading xattrs of %s: %w", f.HostPath, err)
+		}
+	}
+
+	if err := WriteMessage(c.conn, EntryHeader{Path: f.DestPath, Mode: uint32(mode), Size: fi.Size(), XAttrs: xattrs}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(c.conn, in, fi.Size()); err != nil {
+		return fmt.Errorf("copier: streaming %s: %w", f.HostPath, err)
+	}
+	return nil
+}
+
+// Stat asks the helper to stat path (chroot-relative) without reading it.
+func (c *Client) Stat(req StatReq) (Stat, error) {
+	if err := WriteMessage(c.conn, Request{Op: OpStat, Stat: &req}); err != nil {
+		return Stat{}, err
+	}
+	var resp Response
+	if err := ReadMessage(c.conn, &resp); err != nil {
+		return Stat{}, err
+	}
+	if resp.Err != "" {
+		return Stat{}, fmt.Errorf("copier: %s", resp.Err)
+	}
+	if resp.Stat == nil {
+		return Stat{}, fmt.Errorf("copier: stat response missing Stat field")
+	}
+	return *resp.Stat, nil
+}
+
+// Mkdir asks the helper to create path (chroot-relative), including
+// parents.
+func (c *Client) Mkdir(req MkdirReq) error {
+	if err := WriteMessage(c.conn, Request{Op: OpMkdir, Mkdir: &req}); err != nil {
+		return err
+	}
+	var resp Response
+	if err := ReadMessage(c.conn, &resp); err != nil {
+		return err
+	}
+	if resp.Err != "" {
+		return fmt.Errorf("copier: %s", resp.Err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package copier implements a COPY/ADD file-transfer protocol for the
+// Dockerfile builder; the builder's performCopy does not call it and copies
+// through its own archiver and file-copy path. A parent process issues
+// requests over a Unix socket
+// to a child chroot'ed into the destination RWLayer, which performs the
+// writes. This keeps every path that touches the destination filesystem
+// chroot'ed in one audited process, instead of relying on each caller to get
+// chrootarchive invocations right, and lets large single-file COPYs stream
+// instead of being buffered into an intermediate tar stream.
+//
+// A Put exchange looks like:
+//
+//	Request{Op: OpPut, Put: &PutReq{...}}
+//	EntryHeader{Path: "a", Size: 123}, <123 bytes of file "a">
+//	EntryHeader{Path: "b", Size: 456}, <456 bytes of file "b">
+//	EntryHeader{Done: true}
+//	Response{Entries: [...]}
+//
+// The client (which is not chroot'ed, and so can freely walk the build
+// context) drives the walk and streams bytes; the server (chroot'ed into
+// the destination) only ever writes at paths relative to its own root.
+package copier
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Op identifies the kind of request sent over the protocol connection.
+type Op string
+
+const (
+	OpPut   Op = "put"
+	OpStat  Op = "stat"
+	OpMkdir Op = "mkdir"
+)
+
+// Request is a single length-prefixed JSON message sent from the parent to
+// the chroot helper. Exactly one of the Op-specific fields is populated,
+// matching Op.
+type Request struct {
+	Op    Op        `json:"op"`
+	Put   *PutReq   `json:"put,omitempty"`
+	Stat  *StatReq  `json:"stat,omitempty"`
+	Mkdir *MkdirReq `json:"mkdir,omitempty"`
+}
+
+// PutReq opens a Put exchange: Dest (chroot-relative) is the directory
+// entries are written under, with the given ownership/mode/xattr policy.
+// That policy is applied to regular-file entries only: directories are
+// created with their EntryHeader's Mode, symlinks as they are.
+// It is followed on the wire by one (EntryHeader, body) pair per file, then
+// a final EntryHeader{Done: true}.
+type PutReq struct {
+	Dest              string  `json:"dest"`
+	UID               int     `json:"uid"`
+	GID               int     `json:"gid"`
+	ChownUID          *int    `json:"chown_uid,omitempty"`
+	ChownGID          *int    `json:"chown_gid,omitempty"`
+	Mode              *uint32 `json:"mode,omitempty"`
+	PreserveOwnership bool    `json:"preserve_ownership"`
+	PreserveXAttrs    bool    `json:"preserve_xattrs"`
+}
+
+// EntryHeader precedes each file body streamed as part of a Put exchange.
+// A header with Done set terminates the exchange without a body. A symlink
+// entry has no body: LinkTarget carries the link's target text and Size is
+// 0, matching how a tar header represents a symlink (TypeSymlink with
+// Linkname set, no data section) rather than dereferencing it.
+type EntryHeader struct {
+	Done       bool    `json:"done,omitempty"`
+	Path       string  `json:"path,omitempty"` // dest-relative
+	IsDir      bool    `json:"is_dir,omitempty"`
+	IsSymlink  bool    `json:"is_symlink,omitempty"`
+	LinkTarget string  `json:"link_target,omitempty"`
+	Mode       uint32  `json:"mode,omitempty"`
+	Size       int64   `json:"size,omitempty"`
+	XAttrs     []XAttr `json:"xattrs,omitempty"`
+}
+
+// StatReq asks the helper to stat Path (chroot-relative) without reading it.
+type StatReq struct {
+	Path string `json:"path"`
+}
+
+// MkdirReq asks the helper to create Path (chroot-relative), including
+// parents, with the given ownership and mode.
+type MkdirReq struct {
+	Path string `json:"path"`
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+	Mode uint32 `json:"mode"`
+}
+
+// Response is the reply to a Request. Err is non-empty on failure. A Put
+// exchange's Response reports one Entry per file written, each carrying the
+// sha256 digest of the bytes written (a symlink entry carries
+// "symlink:<target>" instead). The builder's cache-probe keys
+// (getSourceHashFromInfos) do not use these digests.
+type Response struct {
+	Err     string  `json:"err,omitempty"`
+	Entries []Entry `json:"entries,omitempty"`
+	Stat    *Stat   `json:"stat,omitempty"`
+}
+
+// Entry describes one file written by a Put.
+type Entry struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// Stat describes the result of a Stat request.
+type Stat struct {
+	Path    string `json:"path"`
+	Mode    uint32 `json:"mode"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"is_dir"`
+	ModTime int64  `json:"mod_time"` // unix seconds
+}
+
+// WriteMessage writes v to w as a 4-byte big-endian length prefix followed
+// by its JSON encoding.
+func WriteMessage(w io.Writer, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("copier: encoding message: %w", err)
+	}
+	var lenPrefix [4]byte
+	binary.BigEndian.PutUint32(lenPrefix[:], uint32(len(body)))
+	if _, err := w.Write(lenPrefix[:]); err != nil {
+		return fmt.Errorf("copier: writing message length: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("copier: writing message body: %w", err)
+	}
+	return nil
+}
+
+// ReadMessage reads one length-prefixed JSON message written by
+// WriteMessage into v.
+func ReadMessage(r io.Reader, v any) error {
+	var lenPrefix [4]byte
+	if _, err := io.ReadFull(r, lenPrefix[:]); err != nil {
+		return fmt.Errorf("copier: reading message length: %w", err)
+	}
+	size := binary.BigEndian.Uint32(lenPrefix[:])
+	const maxMessageSize = 64 << 20
+	if size > maxMessageSize {
+		return fmt.Errorf("copier: message of %d bytes exceeds limit of %d", size, maxMessageSize)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return fmt.Errorf("copier: reading message body: %w", err)
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,90 @@
+package copier
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/moby/sys/reexec"
+)
+
+// reexecCommand is the name under which the chroot helper re-executes
+// itself; registered in init() so it's available as soon as the daemon
+// binary starts, same as chrootarchive's own helper.
+const reexecCommand = "docker-copier"
+
+func init() {
+	reexec.Register(reexecCommand, chrootHelperMain)
+}
+
+// Spawn forks a copier helper chrooted into root and connected to the
+// returned Client over a Unix socketpair. Closing the Client ends the
+// helper's Serve loop; callers must then Wait on the returned *os.Process to
+// reap the helper.
+func Spawn(root string) (*Client, *os.Process, error) {
+	parentConn, childFile, err := socketpair()
+	if err != nil {
+		return nil, nil, fmt.Errorf("copier: creating socketpair: %w", err)
+	}
+	defer childFile.Close()
+
+	cmd := reexec.Command(reexecCommand, root)
+	cmd.ExtraFiles = []*os.File{childFile}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		parentConn.Close()
+		return nil, nil, fmt.Errorf("copier: starting chroot helper: %w", err)
+	}
+
+	return NewClient(parentConn), cmd.Process, nil
+}
+
+func socketpair() (net.Conn, *os.File, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentFile := os.NewFile(uintptr(fds[0]), "copier-parent")
+	childFile := os.NewFile(uintptr(fds[1]), "copier-child")
+	defer parentFile.Close()
+
+	parentConn, err := net.FileConn(parentFile)
+	if err != nil {
+		childFile.Close()
+		return nil, nil, err
+	}
+	return parentConn, childFile, nil
+}
+
+// chrootHelperMain is the reexec entrypoint: it chroots into the path given
+// as its first argument, then serves the protocol on fd 3 (the child side
+// of the socketpair Spawn created) until the connection closes.
+func chrootHelperMain() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "copier: missing chroot root argument")
+		os.Exit(1)
+	}
+	root := os.Args[1]
+
+	if err := syscall.Chroot(root); err != nil {
+		fmt.Fprintf(os.Stderr, "copier: chroot to %s: %v\n", root, err)
+		os.Exit(1)
+	}
+	if err := os.Chdir("/"); err != nil {
+		fmt.Fprintf(os.Stderr, "copier: chdir to /: %v\n", err)
+		os.Exit(1)
+	}
+
+	connFile := os.NewFile(3, "copier-conn")
+	conn, err := net.FileConn(connFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "copier: wrapping fd 3 as conn: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := Serve(conn); err != nil {
+		fmt.Fprintf(os.Stderr, "copier: serving: %v\n", err)
+		os.Exit(1)
+	}
+}
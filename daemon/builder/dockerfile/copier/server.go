@@ -0,0 +1,189 @@
+package copier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Serve runs the chroot-helper side of the protocol on conn until the
+// connection is closed or a request fails to decode. It is expected to run
+// after the caller has chrooted into the destination RWLayer root; paths in
+// requests are resolved relative to that chroot, i.e. "/".
+func Serve(conn net.Conn) error {
+	for {
+		var req Request
+		if err := ReadMessage(conn, &req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var resp Response
+		switch req.Op {
+		case OpPut:
+			if req.Put == nil {
+				resp = errResponse(fmt.Errorf("put request missing body"))
+				break
+			}
+			resp = handlePut(conn, *req.Put)
+		case OpStat:
+			if req.Stat == nil {
+				resp = errResponse(fmt.Errorf("stat request missing body"))
+				break
+			}
+			resp = handleStat(*req.Stat)
+		case OpMkdir:
+			if req.Mkdir == nil {
+				resp = errResponse(fmt.Errorf("mkdir request missing body"))
+				break
+			}
+			resp = handleMkdir(*req.Mkdir)
+		default:
+			resp = errResponse(fmt.Errorf("unknown op %q", req.Op))
+		}
+
+		if err := WriteMessage(conn, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func errResponse(err error) Response {
+	return Response{Err: err.Error()}
+}
+
+func handleMkdir(req MkdirReq) Response {
+	if err := os.MkdirAll(req.Path, os.FileMode(req.Mode)); err != nil {
+		return errResponse(err)
+	}
+	if err := os.Chown(req.Path, req.UID, req.GID); err != nil {
+		return errResponse(err)
+	}
+	return Response{}
+}
+
+func handleStat(req StatReq) Response {
+	fi, err := os.Lstat(req.Path)
+	if err != nil {
+		return errResponse(err)
+	}
+	return Response{Stat: &Stat{
+		Path:    req.Path,
+		Mode:    uint32(fi.Mode()),
+		Size:    fi.Size(),
+		IsDir:   fi.IsDir(),
+		ModTime: fi.ModTime().Unix(),
+	}}
+}
+
+// handlePut reads the (EntryHeader, body) stream that follows req on conn
+// until a Done header arrives, writing each file under req.Dest.
+func handlePut(conn net.Conn, req PutReq) Response {
+	var entries []Entry
+	for {
+		var hdr EntryHeader
+		if err := ReadMessage(conn, &hdr); err != nil {
+			return errResponse(fmt.Errorf("reading entry header: %w", err))
+		}
+		if hdr.Done {
+			return Response{Entries: entries}
+		}
+
+		dest, err := resolveEntryDest(req.Dest, hdr.Path)
+		if err != nil {
+			return errResponse(err)
+		}
+		if hdr.IsDir {
+			if err := os.MkdirAll(dest, os.FileMode(hdr.Mode)); err != nil {
+				return errResponse(err)
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return errResponse(err)
+		}
+
+		if hdr.IsSymlink {
+			// No body follows a symlink entry: recreate the link itself
+			// rather than reading bytes, so a COPY of a symlink lands as a
+			// symlink on the destination instead of a copy of its target's
+			// contents.
+			_ = os.Remove(dest)
+			if err := os.Symlink(hdr.LinkTarget, dest); err != nil {
+				return errResponse(fmt.Errorf("creating symlink %s: %w", dest, err))
+			}
+			entries = append(entries, Entry{Path: hdr.Path, Digest: "symlink:" + hdr.LinkTarget})
+			continue
+		}
+
+		entry, err := writeOne(conn, dest, hdr, req)
+		if err != nil {
+			return errResponse(err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// resolveEntryDest joins root and entryPath, rejecting any entryPath that
+// would escape root. entryPath comes from the wire protocol, so it must be
+// treated the same way a tar header name is by chrootarchive: an absolute
+// path or a "../" segment must not be allowed to land outside root, even
+// though the process is chrooted, since root itself may not be "/".
+func resolveEntryDest(root, entryPath string) (string, error) {
+	if filepath.IsAbs(entryPath) {
+		return "", fmt.Errorf("entry path %q must not be absolute", entryPath)
+	}
+	dest := filepath.Join(root, entryPath)
+	rel, err := filepath.Rel(root, dest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("entry path %q escapes destination %q", entryPath, root)
+	}
+	return dest, nil
+}
+
+func writeOne(conn net.Conn, dest string, hdr EntryHeader, req PutReq) (Entry, error) {
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
+	if err != nil {
+		return Entry{}, fmt.Errorf("opening %s: %w", dest, err)
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(out, hasher), conn, hdr.Size); err != nil {
+		return Entry{}, fmt.Errorf("writing %s: %w", dest, err)
+	}
+
+	uid, gid := req.UID, req.GID
+	if req.ChownUID != nil {
+		uid = *req.ChownUID
+	}
+	if req.ChownGID != nil {
+		gid = *req.ChownGID
+	}
+	if !req.PreserveOwnership {
+		if err := out.Chown(uid, gid); err != nil {
+			return Entry{}, fmt.Errorf("chowning %s: %w", dest, err)
+		}
+	}
+	if req.Mode != nil {
+		if err := out.Chmod(os.FileMode(*req.Mode)); err != nil {
+			return Entry{}, fmt.Errorf("chmoding %s: %w", dest, err)
+		}
+	}
+	if req.PreserveXAttrs && len(hdr.XAttrs) > 0 {
+		if err := applyXAttrs(dest, hdr.XAttrs); err != nil {
+			return Entry{}, fmt.Errorf("applying xattrs to %s: %w", dest, err)
+		}
+	}
+
+	return Entry{Path: hdr.Path, Digest: "sha256:" + hex.EncodeToString(hasher.Sum(nil)), Size: hdr.Size}, nil
+}
@@ -0,0 +1,39 @@
+package copier
+
+import "testing"
+
+func TestResolveEntryDest(t *testing.T) {
+	const root = "/dest"
+
+	valid := []struct {
+		entryPath string
+		want      string
+	}{
+		{"file.txt", "/dest/file.txt"},
+		{"sub/dir/file.txt", "/dest/sub/dir/file.txt"},
+		{".", "/dest"},
+	}
+	for _, tc := range valid {
+		got, err := resolveEntryDest(root, tc.entryPath)
+		if err != nil {
+			t.Errorf("resolveEntryDest(%q, %q) returned error: %v", root, tc.entryPath, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("resolveEntryDest(%q, %q) = %q, want %q", root, tc.entryPath, got, tc.want)
+		}
+	}
+
+	invalid := []string{
+		"/etc/passwd",
+		"../escape",
+		"../../escape",
+		"sub/../../escape",
+		"..",
+	}
+	for _, entryPath := range invalid {
+		if _, err := resolveEntryDest(root, entryPath); err == nil {
+			t.Errorf("resolveEntryDest(%q, %q) did not return an error, expected one", root, entryPath)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package copier
+
+import "github.com/docker/docker/daemon/builder/dockerfile/internal/xattr"
+
+// XAttr is an alias for xattr.XAttr rather than an independent
+// implementation: internal/xattr already lists, reads, and applies xattrs
+// for a plain os-level COPY, and the wire-protocol version here preserves
+// the exact same namespace allow-list, so the two should not drift out of
+// sync with each other.
+//
+// This imports the dockerfile package's internal/xattr leaf package rather
+// than the dockerfile package itself, so that the parent dockerfile package
+// can import copier without an import cycle.
+type XAttr = xattr.XAttr
+
+// readXAttrs reads the preserved xattrs of path, for the client side of a
+// Put to attach to the EntryHeader it sends for that file.
+func readXAttrs(path string) ([]XAttr, error) {
+	return xattr.ReadXAttrs(path)
+}
+
+// applyXAttrs re-applies attrs (as read by readXAttrs) onto path, for the
+// server side of a Put once the destination file exists.
+func applyXAttrs(path string, attrs []XAttr) error {
+	return xattr.ApplyXAttrs(path, attrs)
+}
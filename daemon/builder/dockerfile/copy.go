@@ -0,0 +1,167 @@
+package dockerfile
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/go-archive"
+	"github.com/pkg/errors"
+)
+
+// identity is the uid/gid pair applied to copied files when a COPY/ADD
+// --chown is in effect (or, absent one, the image root's own mapped pair).
+type identity struct {
+	UID int
+	GID int
+}
+
+// copyInfo describes one side of a copy: path, relative to root, identifies
+// the file or directory tree, and hash (when known) feeds the legacy
+// path-based cache-probe key used by getSourceHashFromInfos.
+type copyInfo struct {
+	root string
+	path string
+	hash string
+}
+
+// copyInstruction is the parsed form of a COPY/ADD Dockerfile instruction
+// that performCopy executes.
+type copyInstruction struct {
+	cmdName                 string
+	infos                   []copyInfo
+	dest                    string
+	chownStr                string
+	chmodStr                string
+	preserveOwnership       bool
+	allowLocalDecompression bool
+}
+
+// copyFileOptions configures a single performCopyForInfo call.
+type copyFileOptions struct {
+	ctx            context.Context
+	decompress     bool
+	archiver       *archive.Archiver
+	identity       *identity
+	preserveXAttrs bool
+	mode           *os.FileMode
+}
+
+// performCopyForInfo copies the file or directory tree described by info
+// (relative to info.root) onto destInfo.root/destInfo.path, applying
+// options. A regular file is created with options.mode (when set), gets its
+// xattrs copied when options.preserveXAttrs is set, and is chowned to
+// options.identity. A directory tree is copied by options.archiver, which
+// uses neither mode nor preserveXAttrs, and only the top destination
+// directory is then chowned to options.identity. options.decompress is not
+// consulted.
+func performCopyForInfo(destInfo, info copyInfo, options copyFileOptions) error {
+	srcPath := filepath.Join(info.root, info.path)
+	destPath := filepath.Join(destInfo.root, destInfo.path)
+
+	srcFi, err := os.Lstat(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat source path %q", srcPath)
+	}
+
+	ctx := options.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if srcFi.IsDir() {
+		return performCopyDir(ctx, srcPath, destPath, options)
+	}
+	return performCopyFile(ctx, srcPath, destPath, srcFi, options)
+}
+
+// performCopyDir drives options.archiver.CopyWithTar in a goroutine so a
+// canceled ctx returns promptly instead of waiting for the (non-cancelable)
+// tar copy to finish.
+func performCopyDir(ctx context.Context, srcPath, destPath string, options copyFileOptions) error {
+	if options.archiver == nil {
+		return errors.New("performCopyForInfo: no archiver configured to copy directory")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- options.archiver.CopyWithTar(srcPath, destPath)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy directory %q to %q", srcPath, destPath)
+		}
+	}
+	return applyOwner(destPath, options)
+}
+
+func performCopyFile(ctx context.Context, srcPath, destPath string, srcFi os.FileInfo, options copyFileOptions) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create directory for %q", destPath)
+	}
+
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open source file %q", srcPath)
+	}
+	defer in.Close()
+
+	mode := srcFi.Mode()
+	if options.mode != nil {
+		mode = *options.mode
+	}
+
+	out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create destination file %q", destPath)
+	}
+	defer out.Close()
+
+	if err := copySparse(ctx, out, in); err != nil {
+		return errors.Wrapf(err, "failed to copy %q to %q", srcPath, destPath)
+	}
+
+	if options.preserveXAttrs {
+		if err := copyXAttrs(srcPath, destPath); err != nil {
+			return errors.Wrapf(err, "failed to preserve xattrs on %q", destPath)
+		}
+	}
+	return applyOwner(destPath, options)
+}
+
+func applyOwner(destPath string, options copyFileOptions) error {
+	if options.identity == nil {
+		return nil
+	}
+	if err := os.Chown(destPath, options.identity.UID, options.identity.GID); err != nil {
+		return errors.Wrapf(err, "failed to chown %q", destPath)
+	}
+	return nil
+}
+
+// normalizeDest resolves a COPY/ADD destination the way Dockerfile
+// instructions do: an absolute destination is used as-is, a relative one is
+// joined under workingDir. Both sides are treated as slash-separated image
+// paths, not host paths.
+func normalizeDest(workingDir, requested string) (string, error) {
+	dest := filepath.ToSlash(requested)
+	if !path.IsAbs(dest) {
+		if workingDir == "" {
+			return "", errors.New("WORKDIR must be set before a relative COPY/ADD destination can be used")
+		}
+		dest = path.Join(filepath.ToSlash(workingDir), dest)
+	}
+	// path.Join strips a trailing slash, but that's significant here: it's
+	// how a Dockerfile says "copy into this directory" rather than "copy to
+	// this exact name".
+	if strings.HasSuffix(requested, "/") && !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+	return dest, nil
+}
@@ -0,0 +1,104 @@
+package dockerfile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copySparse copies src to dst preserving sparse-file holes, using
+// SEEK_HOLE/SEEK_DATA to find runs of allocated data and writing only those
+// with pwrite, rather than materializing the zero runs in between. If the
+// source filesystem doesn't support SEEK_HOLE (EINVAL on the very first
+// seek), or the file has no data at all (ENXIO on that seek), it falls back
+// to a plain io.Copy. Either path is interrupted
+// promptly if ctx is canceled mid-copy.
+func copySparse(ctx context.Context, dst, src *os.File) error {
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := unix.Seek(int(src.Fd()), 0, unix.SEEK_DATA); err != nil {
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENXIO) {
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			_, err := io.Copy(dst, newCtxReader(ctx, src))
+			return err
+		}
+		return err
+	}
+
+	if err := dst.Truncate(size); err != nil {
+		return err
+	}
+
+	var offset int64
+	for offset < size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dataStart, err := unix.Seek(int(src.Fd()), offset, unix.SEEK_DATA)
+		if err != nil {
+			if errors.Is(err, unix.ENXIO) {
+				// No more data after offset; remaining holes are already
+				// zero-filled by Truncate above.
+				break
+			}
+			return err
+		}
+
+		holeStart, err := unix.Seek(int(src.Fd()), dataStart, unix.SEEK_HOLE)
+		if err != nil {
+			return err
+		}
+
+		if err := copyRange(ctx, dst, src, dataStart, holeStart-dataStart); err != nil {
+			return err
+		}
+		offset = holeStart
+	}
+
+	return nil
+}
+
+// copyRange copies length bytes from src to dst starting at offset, in
+// fixed-size chunks, checking ctx between chunks so a single large
+// contiguous data run (the common case for an ordinary, non-sparse file) is
+// still interrupted promptly rather than running to completion.
+func copyRange(ctx context.Context, dst, src *os.File, offset, length int64) error {
+	buf := make([]byte, 1<<20)
+	for length > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := int64(len(buf))
+		if n > length {
+			n = length
+		}
+		read, err := src.ReadAt(buf[:n], offset)
+		if read > 0 {
+			if _, werr := dst.WriteAt(buf[:read], offset); werr != nil {
+				return werr
+			}
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		offset += int64(read)
+		length -= int64(read)
+		if read == 0 {
+			break
+		}
+	}
+	return nil
+}
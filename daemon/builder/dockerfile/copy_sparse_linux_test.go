@@ -0,0 +1,104 @@
+package dockerfile
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCopyRangeAbortsOnCancel exercises the scenario the request itself
+// calls out: a large file with a single contiguous data run (the common,
+// non-sparse case) must still be interrupted promptly on ctx cancellation,
+// not just in between SEEK_HOLE/SEEK_DATA runs.
+func TestCopyRangeAbortsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 8<<20) // several 1MiB chunks; ctx is canceled before the call, so copyRange must stop at its first chunk check
+	for i := range data {
+		data[i] = 'x'
+	}
+
+	srcPath := filepath.Join(dir, "src")
+	if err := os.WriteFile(srcPath, data, 0o644); err != nil {
+		t.Fatalf("WriteFile(src): %v", err)
+	}
+	dstPath := filepath.Join(dir, "dst")
+	if err := os.WriteFile(dstPath, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile(dst): %v", err)
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		t.Fatalf("Open(src): %v", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile(dst): %v", err)
+	}
+	defer dst.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- copyRange(ctx, dst, src, 0, int64(len(data)))
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("copyRange() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("copyRange did not return within a bounded time after ctx was canceled")
+	}
+}
+
+// TestCopySparseAbortsOnCancel is the copySparse-level equivalent: a whole
+// (non-sparse) file is one contiguous data run, so this must return
+// promptly on a canceled ctx rather than copying the full file first.
+func TestCopySparseAbortsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 8<<20)
+	for i := range data {
+		data[i] = 'y'
+	}
+
+	srcPath := filepath.Join(dir, "src")
+	if err := os.WriteFile(srcPath, data, 0o644); err != nil {
+		t.Fatalf("WriteFile(src): %v", err)
+	}
+	dstPath := filepath.Join(dir, "dst")
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		t.Fatalf("Open(src): %v", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile(dst): %v", err)
+	}
+	defer dst.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- copySparse(ctx, dst, src)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("copySparse() returned nil error, want context.Canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("copySparse did not return within a bounded time after ctx was canceled")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package dockerfile
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// copySparse copies src to dst. Platforms other than linux don't get the
+// SEEK_HOLE/SEEK_DATA treatment; ctx still interrupts a long copy.
+func copySparse(ctx context.Context, dst, src *os.File) error {
+	_, err := io.Copy(dst, newCtxReader(ctx, src))
+	return err
+}
@@ -0,0 +1,13 @@
+package dockerfile
+
+import "github.com/docker/docker/daemon/builder/dockerfile/internal/xattr"
+
+// copyXAttrs copies the extended attributes preserved by the internal/xattr
+// package from src onto dst.
+func copyXAttrs(src, dst string) error {
+	attrs, err := xattr.ReadXAttrs(src)
+	if err != nil {
+		return err
+	}
+	return xattr.ApplyXAttrs(dst, attrs)
+}
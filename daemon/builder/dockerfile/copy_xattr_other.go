@@ -0,0 +1,9 @@
+//go:build !linux
+
+package dockerfile
+
+// copyXAttrs is a no-op on platforms without the namespaces (security.*,
+// user.*, POSIX ACLs) internal/xattr preserves on Linux.
+func copyXAttrs(src, dst string) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package dockerfile
+
+import (
+	"context"
+	"io"
+)
+
+// ctxCheckInterval is how many bytes ctxReader lets through between
+// ctx.Err() checks, so a canceled build aborts a large COPY/ADD promptly
+// without paying for a context check on every small Read call.
+const ctxCheckInterval = 32 << 20 // 32MiB
+
+// ctxReader wraps an io.Reader and returns ctx.Err() once at least
+// ctxCheckInterval bytes have been read since the last check, so a long
+// file stream (as copied by copySparse for performCopyForInfo) can be
+// aborted mid-copy instead of running to completion after the build's
+// context is canceled.
+type ctxReader struct {
+	ctx          context.Context
+	r            io.Reader
+	sinceLastChk int64
+}
+
+// newCtxReader wraps r so reads from it are interrupted by ctx cancellation.
+func newCtxReader(ctx context.Context, r io.Reader) io.Reader {
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if c.sinceLastChk >= ctxCheckInterval {
+		c.sinceLastChk = 0
+		if err := c.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.r.Read(p)
+	c.sinceLastChk += int64(n)
+	return n, err
+}
@@ -0,0 +1,52 @@
+package dockerfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCtxReaderPassesThroughUncanceled(t *testing.T) {
+	want := bytes.Repeat([]byte("a"), 1024)
+	r := newCtxReader(context.Background(), bytes.NewReader(want))
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ctxReader altered the stream: got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestCtxReaderAbortsAfterCancelOnceIntervalElapsed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := &ctxReader{ctx: ctx, r: bytes.NewReader(bytes.Repeat([]byte("a"), 16))}
+	// Force the next Read to perform a ctx.Err() check regardless of
+	// ctxCheckInterval's real value.
+	r.sinceLastChk = ctxCheckInterval
+
+	_, err := r.Read(make([]byte, 4))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCtxReaderDoesNotCheckBeforeInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := &ctxReader{ctx: ctx, r: bytes.NewReader([]byte("a"))}
+
+	n, err := r.Read(make([]byte, 1))
+	if err != nil {
+		t.Errorf("Read() before interval elapsed returned error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Read() = %d bytes, want 1", n)
+	}
+}
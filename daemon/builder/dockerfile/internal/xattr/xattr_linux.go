@@ -0,0 +1,137 @@
+// Package xattr lists, reads, and applies preserved extended attributes for
+// a COPY/ADD source file.
+//
+// It's a leaf package specifically so both daemon/builder/dockerfile (for a
+// plain os-level copy) and its copier subpackage (for the chroot'ed
+// client/server wire protocol) can import it without the two importing each
+// other.
+package xattr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// XAttr is a single extended attribute name/value pair read from a source
+// file by ReadXAttrs and later re-applied by ApplyXAttrs.
+type XAttr struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"`
+}
+
+// prefixesToPreserve lists the xattr namespaces ReadXAttrs will carry over
+// from source to destination. ACL xattrs are only meaningful when the
+// copying process has CAP_SYS_ADMIN (or owns the file and the filesystem
+// honours POSIX ACLs), so callers without that capability will simply see
+// Lsetxattr fail with EPERM/ENOTSUP for those names, which ApplyXAttrs
+// treats as a no-op rather than an error.
+var prefixesToPreserve = []string{
+	"security.capability",
+	"user.",
+	"system.posix_acl_access",
+	"system.posix_acl_default",
+}
+
+// ReadXAttrs reads the preserved xattrs (those matching prefixesToPreserve)
+// set on path.
+func ReadXAttrs(path string) ([]XAttr, error) {
+	names, err := listXAttrs(path)
+	if err != nil {
+		if isNotSupported(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing xattrs of %s: %w", path, err)
+	}
+
+	var attrs []XAttr
+	for _, name := range names {
+		if !shouldPreserve(name) {
+			continue
+		}
+		value, err := getXAttr(path, name)
+		if err != nil {
+			if isNotSupported(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading xattr %s of %s: %w", name, path, err)
+		}
+		attrs = append(attrs, XAttr{Name: name, Value: value})
+	}
+	return attrs, nil
+}
+
+// ApplyXAttrs re-applies attrs (as read by ReadXAttrs) onto path.
+func ApplyXAttrs(path string, attrs []XAttr) error {
+	for _, attr := range attrs {
+		if err := unix.Lsetxattr(path, attr.Name, attr.Value, 0); err != nil {
+			if isNotSupported(err) {
+				continue
+			}
+			return fmt.Errorf("setting xattr %s on %s: %w", attr.Name, path, err)
+		}
+	}
+	return nil
+}
+
+func shouldPreserve(name string) bool {
+	for _, prefix := range prefixesToPreserve {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func listXAttrs(path string) ([]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	n, err := unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+	return splitNullTerminated(buf[:n]), nil
+}
+
+func getXAttr(path, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	n, err := unix.Lgetxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func splitNullTerminated(buf []byte) []string {
+	var names []string
+	start := 0
+	for i, b := range buf {
+		if b != 0 {
+			continue
+		}
+		if i > start {
+			names = append(names, string(buf[start:i]))
+		}
+		start = i + 1
+	}
+	return names
+}
+
+func isNotSupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EPERM)
+}
@@ -0,0 +1,60 @@
+package xattr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReadApplyRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	for _, p := range []string{src, dst} {
+		if err := os.WriteFile(p, []byte("content"), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s): %v", p, err)
+		}
+	}
+
+	if err := unix.Setxattr(src, "user.test", []byte("value"), 0); err != nil {
+		t.Skipf("user xattrs unsupported on this filesystem: %v", err)
+	}
+
+	attrs, err := ReadXAttrs(src)
+	if err != nil {
+		t.Fatalf("ReadXAttrs: %v", err)
+	}
+	if err := ApplyXAttrs(dst, attrs); err != nil {
+		t.Fatalf("ApplyXAttrs: %v", err)
+	}
+
+	size, err := unix.Getxattr(dst, "user.test", nil)
+	if err != nil {
+		t.Fatalf("Getxattr(dst): %v", err)
+	}
+	buf := make([]byte, size)
+	if _, err := unix.Getxattr(dst, "user.test", buf); err != nil {
+		t.Fatalf("Getxattr(dst) value: %v", err)
+	}
+	if string(buf) != "value" {
+		t.Errorf("copied xattr value = %q, want %q", buf, "value")
+	}
+}
+
+func TestShouldPreserve(t *testing.T) {
+	preserve := []string{"security.capability", "user.foo", "system.posix_acl_access", "system.posix_acl_default"}
+	for _, name := range preserve {
+		if !shouldPreserve(name) {
+			t.Errorf("shouldPreserve(%q) = false, want true", name)
+		}
+	}
+
+	drop := []string{"trusted.overlay.opaque", "system.other", ""}
+	for _, name := range drop {
+		if shouldPreserve(name) {
+			t.Errorf("shouldPreserve(%q) = true, want false", name)
+		}
+	}
+}
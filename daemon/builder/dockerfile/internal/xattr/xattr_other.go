@@ -0,0 +1,19 @@
+//go:build !linux
+
+package xattr
+
+// XAttr is a single extended attribute name/value pair. Reading and
+// applying xattrs is a no-op on platforms without the namespaces (security.*,
+// user.*, POSIX ACLs) this package preserves on Linux.
+type XAttr struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"`
+}
+
+func ReadXAttrs(path string) ([]XAttr, error) {
+	return nil, nil
+}
+
+func ApplyXAttrs(path string, attrs []XAttr) error {
+	return nil
+}
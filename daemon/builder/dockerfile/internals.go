@@ -8,6 +8,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/containerd/log"
@@ -127,7 +129,11 @@ func (b *Builder) performCopy(ctx context.Context, req dispatchRequest, inst cop
 	if inst.chownStr != "" {
 		chownComment = fmt.Sprintf("--chown=%s ", inst.chownStr)
 	}
-	commentStr := fmt.Sprintf("%s %s%s in %s ", inst.cmdName, chownComment, srcHash, inst.dest)
+	var chmodComment string
+	if inst.chmodStr != "" {
+		chmodComment = fmt.Sprintf("--chmod=%s ", inst.chmodStr)
+	}
+	commentStr := fmt.Sprintf("%s %s%s%s in %s ", inst.cmdName, chownComment, chmodComment, srcHash, inst.dest)
 
 	// TODO: should this have been using origPaths instead of srcHash in the comment?
 	runConfigWithCommentCmd := copyRunConfig(state.runConfig, withCmdCommentString(commentStr, state.operatingSystem))
@@ -136,6 +142,29 @@ func (b *Builder) performCopy(ctx context.Context, req dispatchRequest, inst cop
 		return err
 	}
 
+	// The legacy path-hash key above misses whenever a build produces an
+	// identical file tree under a different context path. When opted in,
+	// also probe a content-addressed key computed from the actual bytes.
+	// This is a probe-only, additional lookup: runConfigWithCommentCmd (the
+	// legacy comment) stays the one actually committed below, so a later
+	// build of the same Dockerfile with this option off - or by a builder
+	// that hasn't opted in - still matches the comment this step commits
+	// now. Nothing here commits an image under the content-addressed
+	// comment, so this probe only hits an image whose config already
+	// carries it.
+	if b.options.ContentAddressableCopyCache {
+		caHash, err := contentAddressedSourceHash(inst.infos)
+		if err != nil {
+			return errors.Wrap(err, "failed to compute content-addressed hash for copy instruction")
+		}
+		caCommentStr := fmt.Sprintf("%s %s%s(ca:%s) in %s ", inst.cmdName, chownComment, chmodComment, caHash, inst.dest)
+		runConfigWithCaCommentCmd := copyRunConfig(state.runConfig, withCmdCommentString(caCommentStr, state.operatingSystem))
+		hit, err = b.probeCache(state, runConfigWithCaCommentCmd)
+		if err != nil || hit {
+			return err
+		}
+	}
+
 	imgMount, err := b.imageSources.Get(ctx, state.imageID, true, req.builder.platform)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get destination image %q", state.imageID)
@@ -168,10 +197,32 @@ func (b *Builder) performCopy(ctx context.Context, req dispatchRequest, inst cop
 		}
 	}
 
+	var mode *os.FileMode
+	if inst.chmodStr != "" {
+		if inst.preserveOwnership {
+			return errors.New("the --chmod and --preserve-ownership flags cannot be used together, as --preserve-ownership keeps the source mode bits as-is")
+		}
+		parsedMode, err := parseChmodFlag(inst.chmodStr)
+		if err != nil {
+			return errors.Wrapf(err, "invalid --chmod value %q", inst.chmodStr)
+		}
+		mode = &parsedMode
+	}
+
 	for _, info := range inst.infos {
+		// Check between files, not just once before the loop, so a client
+		// disconnect during a COPY/ADD of many files aborts promptly
+		// instead of running to completion.
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "build canceled during copy")
+		}
+
 		opts := copyFileOptions{
-			decompress: inst.allowLocalDecompression,
-			archiver:   b.getArchiver(),
+			ctx:            ctx,
+			decompress:     inst.allowLocalDecompression,
+			archiver:       b.getArchiver(),
+			preserveXAttrs: b.options.PreserveXAttrs,
+			mode:           mode,
 		}
 		if !inst.preserveOwnership {
 			opts.identity = &id
@@ -183,6 +234,38 @@ func (b *Builder) performCopy(ctx context.Context, req dispatchRequest, inst cop
 	return b.exportImage(ctx, state, rwLayer, imgMount.Image(), runConfigWithCommentCmd)
 }
 
+// parseChmodFlag parses the value of a --chmod flag on COPY/ADD into a
+// os.FileMode. It accepts 3- or 4-digit octal values, matching what
+// buildah/podman accept for the same flag: a 4th leading digit sets the
+// setuid/setgid/sticky bits.
+//
+// Go's os.FileMode encodes those special bits at different positions than
+// mode_t, so the leading digit is translated explicitly rather than cast
+// straight through - os.FileMode(0o4755) is not the same value as
+// ModeSetuid|0o755.
+func parseChmodFlag(chmodStr string) (os.FileMode, error) {
+	if len(chmodStr) != 3 && len(chmodStr) != 4 {
+		return 0, errors.New("value must be a 3 or 4 digit octal number")
+	}
+	mode, err := strconv.ParseUint(chmodStr, 8, 32)
+	if err != nil {
+		return 0, errors.New("value must be a valid octal number")
+	}
+
+	perm := os.FileMode(mode & 0o777)
+	special := mode >> 9
+	if special&0o4 != 0 {
+		perm |= os.ModeSetuid
+	}
+	if special&0o2 != 0 {
+		perm |= os.ModeSetgid
+	}
+	if special&0o1 != 0 {
+		perm |= os.ModeSticky
+	}
+	return perm, nil
+}
+
 func createDestInfo(workingDir string, inst copyInstruction, rwLayer builder.RWLayer) (copyInfo, error) {
 	// Twiddle the destination when it's a relative path - meaning, make it
 	// relative to the WORKINGDIR
@@ -0,0 +1,40 @@
+package dockerfile
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseChmodFlag(t *testing.T) {
+	tests := []struct {
+		chmodStr string
+		want     os.FileMode
+	}{
+		{"755", 0o755},
+		{"0755", 0o755},
+		{"000", 0},
+		{"4755", os.ModeSetuid | 0o755},
+		{"2755", os.ModeSetgid | 0o755},
+		{"1755", os.ModeSticky | 0o755},
+		{"6755", os.ModeSetuid | os.ModeSetgid | 0o755},
+	}
+	for _, tc := range tests {
+		got, err := parseChmodFlag(tc.chmodStr)
+		if err != nil {
+			t.Errorf("parseChmodFlag(%q) returned error: %v", tc.chmodStr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseChmodFlag(%q) = %#o, want %#o", tc.chmodStr, got, tc.want)
+		}
+	}
+}
+
+func TestParseChmodFlagInvalid(t *testing.T) {
+	invalid := []string{"", "75", "99999", "888", "abc", "-755"}
+	for _, chmodStr := range invalid {
+		if _, err := parseChmodFlag(chmodStr); err == nil {
+			t.Errorf("parseChmodFlag(%q) did not return an error, expected one", chmodStr)
+		}
+	}
+}
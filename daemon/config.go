@@ -0,0 +1,25 @@
+package daemon
+
+import "time"
+
+// configStore is the daemon's runtime view of its configuration.
+//
+// This only declares the fields referenced elsewhere in this checkout, not
+// the full daemon configuration (normally github.com/docker/docker/daemon/config.Config).
+type configStore struct {
+	DNSSearch []string
+
+	// CredentialSpecCAFile, when set, pins the CA bundle used to validate
+	// the server certificate for credentialspec=https:// lookups, instead
+	// of the system trust store. Configured via daemon.json's
+	// credential-spec-ca-file.
+	CredentialSpecCAFile string `json:"credential-spec-ca-file,omitempty"`
+
+	// CredentialSpecCacheTTL overrides how long a credential spec fetched
+	// over HTTPS is cached before being re-fetched. Configured via
+	// daemon.json's credential-spec-cache-ttl; as a time.Duration,
+	// encoding/json reads it as an integer count of nanoseconds (e.g.
+	// 900000000000 for 15 minutes), not a duration string. Zero means
+	// defaultCredentialSpecCacheTTL.
+	CredentialSpecCacheTTL time.Duration `json:"credential-spec-cache-ttl,omitempty"`
+}
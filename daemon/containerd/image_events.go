@@ -2,14 +2,24 @@ package containerd
 
 import (
 	"context"
+	"strconv"
 
 	c8dimages "github.com/containerd/containerd/v2/core/images"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/daemon/internal/stringid"
 	"github.com/docker/docker/daemon/server/backend"
 	"github.com/moby/moby/api/types/events"
 )
 
 // LogImageEvent generates an event related to an image with only the default attributes.
 func (i *ImageService) LogImageEvent(ctx context.Context, imageID, refName string, action events.Action) {
+	i.logImageEventWithAttrs(ctx, imageID, refName, action, nil)
+}
+
+// logImageEventWithAttrs generates an event related to an image, same as
+// LogImageEvent, but also merging in extraAttrs, which take precedence over
+// the attributes derived from the image itself.
+func (i *ImageService) logImageEventWithAttrs(ctx context.Context, imageID, refName string, action events.Action, extraAttrs map[string]string) {
 	ctx = context.WithoutCancel(ctx)
 	attributes := map[string]string{}
 
@@ -18,9 +28,29 @@ func (i *ImageService) LogImageEvent(ctx context.Context, imageID, refName strin
 		// image has not been removed yet.
 		// it could be missing if the event is `delete`.
 		copyAttributes(attributes, img.Config.Labels)
+		attributes["imageID"] = stringid.TruncateID(imageID)
+		attributes["digest"] = imageID
+		attributes["os"] = img.OperatingSystem()
+		if img.Architecture != "" {
+			attributes["architecture"] = img.Architecture
+		}
+		if img.Variant != "" {
+			attributes["variant"] = img.Variant
+		}
+		if size, err := i.size(ctx, imageID); err == nil {
+			attributes["size"] = strconv.FormatInt(size, 10)
+		}
 	}
 	if refName != "" {
 		attributes["name"] = refName
+		if action == events.ActionPull || action == events.ActionPush {
+			if named, err := reference.ParseNormalizedNamed(refName); err == nil {
+				attributes["registry"] = reference.Domain(named)
+			}
+		}
+	}
+	for k, v := range extraAttrs {
+		attributes[k] = v
 	}
 	i.eventsService.Log(action, events.ImageEventType, events.Actor{
 		ID:         imageID,
@@ -0,0 +1,242 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// defaultCredentialSpecCacheTTL is used when the daemon configuration does
+// not specify a CredentialSpecCacheTTL.
+const defaultCredentialSpecCacheTTL = 15 * time.Minute
+
+// credentialSpecCacheEntry holds the last-fetched value of a credential spec
+// retrieved over HTTPS, along with its expiry.
+type credentialSpecCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// credentialSpecCache caches credential specs fetched from HTTPS endpoints,
+// keyed by URL, and refreshes them in the background ahead of expiry so
+// long-running containers pick up gMSA rotation without a restart.
+type credentialSpecCache struct {
+	mu      sync.Mutex
+	entries map[string]*credentialSpecCacheEntry
+	client  *http.Client
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newCredentialSpecCache(client *http.Client) *credentialSpecCache {
+	return &credentialSpecCache{
+		entries: make(map[string]*credentialSpecCacheEntry),
+		client:  client,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+func (cc *credentialSpecCache) stop() {
+	cc.stopOnce.Do(func() { close(cc.stopCh) })
+}
+
+// get returns a cached, non-expired credential spec for rawURL if one
+// exists, fetching and caching it otherwise. It also arranges for a
+// background refresh shortly before the entry expires.
+func (cc *credentialSpecCache) get(ctx context.Context, rawURL string, expectedSHA256 string, ttl time.Duration) (string, error) {
+	cc.mu.Lock()
+	entry, ok := cc.entries[rawURL]
+	cc.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		// The cache is keyed by URL alone, so a pinned hash is checked
+		// against the cached value too; on a mismatch, fetch validates it.
+		sum := sha256.Sum256([]byte(entry.value))
+		if expectedSHA256 == "" || strings.EqualFold(hex.EncodeToString(sum[:]), expectedSHA256) {
+			return entry.value, nil
+		}
+	}
+
+	value, err := cc.fetch(ctx, rawURL, expectedSHA256)
+	if err != nil {
+		return "", err
+	}
+
+	cc.store(rawURL, value, ttl)
+	return value, nil
+}
+
+func (cc *credentialSpecCache) store(rawURL, value string, ttl time.Duration) {
+	expiresAt := time.Now().Add(ttl)
+	cc.mu.Lock()
+	cc.entries[rawURL] = &credentialSpecCacheEntry{value: value, expiresAt: expiresAt}
+	cc.mu.Unlock()
+
+	// Refresh shortly before the entry would expire so that in-use specs
+	// never go stale for a running container.
+	refreshIn := ttl - ttl/10
+	if refreshIn <= 0 {
+		refreshIn = ttl
+	}
+	go cc.scheduleRefresh(rawURL, ttl, refreshIn)
+}
+
+func (cc *credentialSpecCache) scheduleRefresh(rawURL string, ttl, refreshIn time.Duration) {
+	timer := time.NewTimer(refreshIn)
+	defer timer.Stop()
+	select {
+	case <-cc.stopCh:
+		return
+	case <-timer.C:
+	}
+
+	// Best-effort: a failed background refresh simply leaves the existing
+	// (possibly now-expired) entry in place; the next caller will retry
+	// synchronously in get().
+	value, err := cc.fetch(context.Background(), rawURL, "")
+	if err != nil {
+		return
+	}
+	cc.store(rawURL, value, ttl)
+}
+
+func (cc *credentialSpecCache) fetch(ctx context.Context, rawURL, expectedSHA256 string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", errdefs.InvalidParameter(errors.Wrap(err, "invalid credential spec URL"))
+	}
+
+	resp, err := cc.client.Do(req)
+	if err != nil {
+		return "", errdefs.Unavailable(errors.Wrapf(err, "failed to fetch credential spec from %s", rawURL))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", errdefs.Unavailable(errors.Wrap(err, "failed to read credential spec response body"))
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// fall through
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", errdefs.InvalidParameter(fmt.Errorf("failed to fetch credential spec from %s: %s", rawURL, resp.Status))
+	default:
+		return "", errdefs.Unavailable(fmt.Errorf("failed to fetch credential spec from %s: %s", rawURL, resp.Status))
+	}
+
+	if expectedSHA256 != "" {
+		sum := sha256.Sum256(body)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), expectedSHA256) {
+			return "", errdefs.InvalidParameter(fmt.Errorf("credential spec fetched from %s does not match pinned sha256=%s", rawURL, expectedSHA256))
+		}
+	}
+
+	return string(body), nil
+}
+
+var (
+	credentialSpecCachesMu sync.Mutex
+	// credentialSpecCaches is keyed by the daemon.json credential-spec-ca-file
+	// value in effect when each entry's client was built, so a config change
+	// to that setting gets its own client/cache instead of silently reusing
+	// whichever CA bundle the first credentialspec=https:// lookup happened
+	// to pin.
+	credentialSpecCaches = make(map[string]*credentialSpecCache)
+)
+
+// credentialSpecHTTPTransport builds the HTTP transport used to fetch
+// credential specs over HTTPS, honouring HTTPS_PROXY and an optional pinned
+// CA bundle configured via daemon.json's credential-spec-ca-file.
+func credentialSpecHTTPTransport(caFile string) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read credential-spec-ca-file %s", caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("credential-spec-ca-file %s does not contain any valid certificates", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// readCredentialSpecHTTPS fetches a credential spec from an HTTPS endpoint,
+// serving cached values within daemonCfg.CredentialSpecCacheTTL and
+// refreshing them in the background ahead of expiry.
+//
+// value is the part of the security option after "https://", optionally
+// followed by a "#sha256=<hex>" fragment pinning the expected content hash.
+func (daemon *Daemon) readCredentialSpecHTTPS(ctx context.Context, daemonCfg *configStore, id, value string) (string, error) {
+	rawURL, fragment, _ := strings.Cut(value, "#")
+	rawURL = "https://" + rawURL
+
+	var expectedSHA256 string
+	if fragment != "" {
+		k, v, ok := strings.Cut(fragment, "=")
+		if !ok || !strings.EqualFold(k, "sha256") || v == "" {
+			return "", errdefs.InvalidParameter(fmt.Errorf("invalid credential spec URL fragment %q for container %s: expected sha256=<hex>", fragment, id))
+		}
+		expectedSHA256 = v
+	}
+
+	caFile := daemonCfg.CredentialSpecCAFile
+	client, err := daemon.credentialSpecCache(caFile)
+	if err != nil {
+		return "", errdefs.System(err)
+	}
+
+	ttl := daemonCfg.CredentialSpecCacheTTL
+	if ttl <= 0 {
+		ttl = defaultCredentialSpecCacheTTL
+	}
+
+	spec, err := client.get(ctx, rawURL, expectedSHA256, ttl)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed handling spec %q for container %s", rawURL, id)
+	}
+	return spec, nil
+}
+
+// credentialSpecCache lazily builds the HTTPS credential spec cache for
+// caFile, reusing it across containers that share the same
+// credential-spec-ca-file so the TTL and background refresher are shared
+// rather than per-container. Keyed by caFile rather than built once for the
+// whole process, so a later container configured with a different CA bundle
+// gets its own client instead of the first one ever resolved.
+func (daemon *Daemon) credentialSpecCache(caFile string) (*credentialSpecCache, error) {
+	credentialSpecCachesMu.Lock()
+	defer credentialSpecCachesMu.Unlock()
+
+	if cc, ok := credentialSpecCaches[caFile]; ok {
+		return cc, nil
+	}
+
+	client, err := credentialSpecHTTPTransport(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cc := newCredentialSpecCache(client)
+	credentialSpecCaches[caFile] = cc
+	return cc, nil
+}
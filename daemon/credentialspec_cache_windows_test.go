@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCAFile(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test-ca"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("pem.Encode: %v", err)
+	}
+	return path
+}
+
+func TestCredentialSpecCacheKeyedByCAFile(t *testing.T) {
+	d := &Daemon{}
+	caFile := writeTestCAFile(t)
+
+	noCA, err := d.credentialSpecCache("")
+	if err != nil {
+		t.Fatalf("credentialSpecCache(\"\"): %v", err)
+	}
+	noCAAgain, err := d.credentialSpecCache("")
+	if err != nil {
+		t.Fatalf("credentialSpecCache(\"\") again: %v", err)
+	}
+	if noCA != noCAAgain {
+		t.Errorf("credentialSpecCache(\"\") returned a different instance on a repeat call with the same caFile")
+	}
+
+	withCA, err := d.credentialSpecCache(caFile)
+	if err != nil {
+		t.Fatalf("credentialSpecCache(%q): %v", caFile, err)
+	}
+	if withCA == noCA {
+		t.Errorf("credentialSpecCache returned the same instance for two different caFile values")
+	}
+
+	withCAAgain, err := d.credentialSpecCache(caFile)
+	if err != nil {
+		t.Fatalf("credentialSpecCache(%q) again: %v", caFile, err)
+	}
+	if withCA != withCAAgain {
+		t.Errorf("credentialSpecCache(%q) returned a different instance on a repeat call with the same caFile", caFile)
+	}
+}
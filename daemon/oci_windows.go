@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -209,7 +210,7 @@ func (daemon *Daemon) createSpec(ctx context.Context, daemonCfg *configStore, c
 		NetworkSharedContainerName: networkSharedContainerID,
 	}
 
-	if err := daemon.createSpecWindowsFields(c, &s, isHyperV); err != nil {
+	if err := daemon.createSpecWindowsFields(ctx, daemonCfg, c, &s, isHyperV); err != nil {
 		return nil, err
 	}
 
@@ -223,7 +224,7 @@ func (daemon *Daemon) createSpec(ctx context.Context, daemonCfg *configStore, c
 }
 
 // Sets the Windows-specific fields of the OCI spec
-func (daemon *Daemon) createSpecWindowsFields(c *container.Container, s *specs.Spec, isHyperV bool) error {
+func (daemon *Daemon) createSpecWindowsFields(ctx context.Context, daemonCfg *configStore, c *container.Container, s *specs.Spec, isHyperV bool) error {
 	s.Hostname = c.FullHostname()
 
 	if len(s.Process.Cwd) == 0 {
@@ -277,10 +278,14 @@ func (daemon *Daemon) createSpecWindowsFields(c *container.Container, s *specs.S
 	// First boot optimization
 	s.Windows.IgnoreFlushesDuringBoot = !c.HasBeenStartedBefore
 
+	// Servicing containers are a short-lived helper run against a base
+	// image to apply an update package; see ServiceContainer.
+	s.Windows.Servicing = c.HostConfig.Servicing
+
 	setResourcesInSpec(c, s, isHyperV)
 
 	// Read and add credentials from the security options if a credential spec has been provided.
-	if err := daemon.setWindowsCredentialSpec(c, s); err != nil {
+	if err := daemon.setWindowsCredentialSpec(ctx, daemonCfg, c, s); err != nil {
 		return err
 	}
 
@@ -349,15 +354,16 @@ var errInvalidCredentialSpecSecOpt = errdefs.InvalidParameter(fmt.Errorf("invali
 
 // setWindowsCredentialSpec sets the spec's `Windows.CredentialSpec`
 // field if relevant
-func (daemon *Daemon) setWindowsCredentialSpec(c *container.Container, s *specs.Spec) error {
+func (daemon *Daemon) setWindowsCredentialSpec(ctx context.Context, daemonCfg *configStore, c *container.Container, s *specs.Spec) error {
 	if c.HostConfig == nil || c.HostConfig.SecurityOpt == nil {
 		return nil
 	}
 
-	// TODO (jrouge/wk8): if provided with several security options, we silently ignore
-	// all but the last one (provided they're all valid, otherwise we do return an error);
-	// this doesn't seem like a great idea?
-	credentialSpec := ""
+	// A container may be given several `credentialspec=...` security options,
+	// one per gMSA identity it needs to authenticate as. Each is resolved to
+	// a JSON credential spec below, and the resulting set is merged into a
+	// single spec via mergeCredentialSpecs.
+	var credentialSpecs []string
 
 	// TODO(thaJeztah): extract validating and parsing SecurityOpt to a reusable function.
 	for _, secOpt := range c.HostConfig.SecurityOpt {
@@ -374,7 +380,10 @@ func (daemon *Daemon) setWindowsCredentialSpec(c *container.Container, s *specs.
 		if !ok || value == "" {
 			return errInvalidCredentialSpecSecOpt
 		}
-		var err error
+		var (
+			credentialSpec string
+			err            error
+		)
 		switch strings.ToLower(scheme) {
 		case "file":
 			credentialSpec, err = readCredentialSpecFile(c.ID, daemon.root, filepath.Clean(value))
@@ -404,21 +413,141 @@ func (daemon *Daemon) setWindowsCredentialSpec(c *container.Container, s *specs.
 			credentialSpec = string(csConfig.Spec.Data)
 		case "raw":
 			credentialSpec = value
+		case "https":
+			credentialSpec, err = daemon.readCredentialSpecHTTPS(ctx, daemonCfg, c.ID, value)
+			if err != nil {
+				return err
+			}
 		default:
 			return errInvalidCredentialSpecSecOpt
 		}
+		if credentialSpec != "" {
+			credentialSpecs = append(credentialSpecs, credentialSpec)
+		}
 	}
 
-	if credentialSpec != "" {
-		if s.Windows == nil {
-			s.Windows = &specs.Windows{}
-		}
-		s.Windows.CredentialSpec = credentialSpec
+	if len(credentialSpecs) == 0 {
+		return nil
+	}
+
+	merged, err := mergeCredentialSpecs(credentialSpecs)
+	if err != nil {
+		return errdefs.InvalidParameter(errors.Wrapf(err, "failed to merge credential specs for container %s", c.ID))
+	}
+
+	if s.Windows == nil {
+		s.Windows = &specs.Windows{}
 	}
+	s.Windows.CredentialSpec = merged
 
 	return nil
 }
 
+// gmsaAccount is a single entry of a credential spec's
+// ActiveDirectoryConfig.GroupManagedServiceAccounts array.
+type gmsaAccount struct {
+	Name  string `json:"Name"`
+	Scope string `json:"Scope,omitempty"`
+}
+
+// activeDirectoryConfig is the ActiveDirectoryConfig section of a Windows
+// credential spec.
+type activeDirectoryConfig struct {
+	GroupManagedServiceAccounts []gmsaAccount `json:"GroupManagedServiceAccounts,omitempty"`
+}
+
+// credentialSpecDoc is the subset of the Windows credential spec JSON format
+// (see https://learn.microsoft.com/virtualization/windowscontainers/manage-containers/manage-serviceaccounts)
+// that mergeCredentialSpecs cares about. When several specs are merged,
+// fields outside this subset are dropped from the result; a single spec is
+// returned unchanged.
+type credentialSpecDoc struct {
+	CmsPlugins            []string               `json:"CmsPlugins,omitempty"`
+	DomainJoinConfig      json.RawMessage        `json:"DomainJoinConfig,omitempty"`
+	HostAccountConfig     json.RawMessage        `json:"HostAccountConfig,omitempty"`
+	ActiveDirectoryConfig *activeDirectoryConfig `json:"ActiveDirectoryConfig,omitempty"`
+}
+
+// mergeCredentialSpecs merges one or more JSON-encoded Windows credential
+// specs into a single spec, allowing a container to authenticate as several
+// gMSA identities at once.
+//
+// The ActiveDirectoryConfig.GroupManagedServiceAccounts arrays of all specs
+// are unioned, deduplicated by account Name. The CmsPlugins, DomainJoinConfig
+// and HostAccountConfig sections are expected to either be identical across
+// all specs that define them, or be defined by only one of them; otherwise
+// this is an error, since there would be no sound way to pick one.
+func mergeCredentialSpecs(rawSpecs []string) (string, error) {
+	if len(rawSpecs) == 1 {
+		return rawSpecs[0], nil
+	}
+
+	merged := credentialSpecDoc{}
+	seenGMSA := make(map[string]struct{})
+
+	for _, raw := range rawSpecs {
+		var doc credentialSpecDoc
+		if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+			return "", errors.Wrap(err, "failed to parse credential spec as JSON")
+		}
+
+		if len(doc.CmsPlugins) > 0 {
+			if len(merged.CmsPlugins) == 0 {
+				merged.CmsPlugins = doc.CmsPlugins
+			} else if !stringSlicesEqual(merged.CmsPlugins, doc.CmsPlugins) {
+				return "", errors.New("credential specs define conflicting CmsPlugins")
+			}
+		}
+
+		if len(doc.DomainJoinConfig) > 0 {
+			if len(merged.DomainJoinConfig) == 0 {
+				merged.DomainJoinConfig = doc.DomainJoinConfig
+			} else if !bytes.Equal(merged.DomainJoinConfig, doc.DomainJoinConfig) {
+				return "", errors.New("credential specs define conflicting DomainJoinConfig")
+			}
+		}
+
+		if len(doc.HostAccountConfig) > 0 {
+			if len(merged.HostAccountConfig) == 0 {
+				merged.HostAccountConfig = doc.HostAccountConfig
+			} else if !bytes.Equal(merged.HostAccountConfig, doc.HostAccountConfig) {
+				return "", errors.New("credential specs define conflicting HostAccountConfig")
+			}
+		}
+
+		if doc.ActiveDirectoryConfig != nil {
+			for _, gmsa := range doc.ActiveDirectoryConfig.GroupManagedServiceAccounts {
+				if _, ok := seenGMSA[gmsa.Name]; ok {
+					continue
+				}
+				seenGMSA[gmsa.Name] = struct{}{}
+				if merged.ActiveDirectoryConfig == nil {
+					merged.ActiveDirectoryConfig = &activeDirectoryConfig{}
+				}
+				merged.ActiveDirectoryConfig.GroupManagedServiceAccounts = append(merged.ActiveDirectoryConfig.GroupManagedServiceAccounts, gmsa)
+			}
+		}
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode merged credential spec")
+	}
+	return string(out), nil
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func setResourcesInSpec(c *container.Container, s *specs.Spec, isHyperV bool) {
 	// In s.Windows.Resources
 	cpuShares := uint16(c.HostConfig.CPUShares)
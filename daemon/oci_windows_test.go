@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeCredentialSpecsSingle(t *testing.T) {
+	raw := `{"CmsPlugins":["ActiveDirectory"]}`
+	got, err := mergeCredentialSpecs([]string{raw})
+	if err != nil {
+		t.Fatalf("mergeCredentialSpecs: %v", err)
+	}
+	if got != raw {
+		t.Errorf("mergeCredentialSpecs([one]) = %q, want unchanged %q", got, raw)
+	}
+}
+
+func TestMergeCredentialSpecsUnionsAndDedupesGMSA(t *testing.T) {
+	a := `{"ActiveDirectoryConfig":{"GroupManagedServiceAccounts":[{"Name":"acct1"}]}}`
+	b := `{"ActiveDirectoryConfig":{"GroupManagedServiceAccounts":[{"Name":"acct1"},{"Name":"acct2"}]}}`
+
+	merged, err := mergeCredentialSpecs([]string{a, b})
+	if err != nil {
+		t.Fatalf("mergeCredentialSpecs: %v", err)
+	}
+
+	var doc credentialSpecDoc
+	if err := json.Unmarshal([]byte(merged), &doc); err != nil {
+		t.Fatalf("unmarshal merged spec: %v", err)
+	}
+	if doc.ActiveDirectoryConfig == nil {
+		t.Fatalf("merged spec has no ActiveDirectoryConfig")
+	}
+	if len(doc.ActiveDirectoryConfig.GroupManagedServiceAccounts) != 2 {
+		t.Errorf("got %d gMSA accounts, want 2 (deduplicated): %+v", len(doc.ActiveDirectoryConfig.GroupManagedServiceAccounts), doc.ActiveDirectoryConfig.GroupManagedServiceAccounts)
+	}
+}
+
+func TestMergeCredentialSpecsConflictingCmsPlugins(t *testing.T) {
+	a := `{"CmsPlugins":["ActiveDirectory"]}`
+	b := `{"CmsPlugins":["Other"]}`
+
+	if _, err := mergeCredentialSpecs([]string{a, b}); err == nil {
+		t.Errorf("mergeCredentialSpecs did not error on conflicting CmsPlugins")
+	}
+}
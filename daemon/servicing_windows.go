@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/daemon/container"
+	"github.com/docker/docker/daemon/internal/image"
+	"github.com/docker/docker/daemon/server/backend"
+	"github.com/docker/docker/errdefs"
+)
+
+// ServiceContainer runs a Windows "servicing" pass against the container
+// identified by id: it clones the container's configuration, forces
+// HostConfig.Servicing so the generated OCI spec sets Windows.Servicing,
+// runs the clone under "<id>_servicing", waits for it to exit, and merges
+// the resulting layer back onto the source container.
+//
+// This restores the base-image servicing workflow relied on by Windows
+// update tooling: a servicing container completes an update inside the
+// image without the caller having to hand-roll the clone/wait/merge dance.
+func (daemon *Daemon) ServiceContainer(ctx context.Context, id string) error {
+	c, err := daemon.GetContainer(id)
+	if err != nil {
+		return err
+	}
+
+	hostConfig := *c.HostConfig
+	hostConfig.Servicing = true
+
+	servicingName := c.ID + "_servicing"
+	ccr, err := daemon.ContainerCreate(ctx, backend.ContainerCreateConfig{
+		Name:       servicingName,
+		Config:     c.Config,
+		HostConfig: &hostConfig,
+	})
+	if err != nil {
+		return errdefs.System(fmt.Errorf("failed to create servicing container for %s: %w", id, err))
+	}
+
+	if err := daemon.ContainerStart(ctx, ccr.ID, backend.ContainerStartConfig{}); err != nil {
+		return errdefs.System(fmt.Errorf("failed to start servicing container %s: %w", ccr.ID, err))
+	}
+
+	waitC, errC := daemon.ContainerWait(ctx, ccr.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errC:
+		return errdefs.System(fmt.Errorf("failed waiting for servicing container %s: %w", ccr.ID, err))
+	case result := <-waitC:
+		if result.ExitCode != 0 {
+			return errdefs.System(fmt.Errorf("servicing container %s exited with code %d", ccr.ID, result.ExitCode))
+		}
+	}
+
+	if err := daemon.mergeServicingLayer(ctx, c, ccr.ID); err != nil {
+		return errdefs.System(fmt.Errorf("failed to merge servicing layer from %s onto %s: %w", ccr.ID, id, err))
+	}
+
+	return nil
+}
+
+// mergeServicingLayer commits the layer produced by the servicing container
+// svcID and stacks it as a new top layer of c's image, so that the update
+// the servicing container applied becomes part of c without requiring c
+// itself to be recreated.
+func (daemon *Daemon) mergeServicingLayer(ctx context.Context, c *container.Container, svcID string) error {
+	svc, err := daemon.GetContainer(svcID)
+	if err != nil {
+		return err
+	}
+
+	newLayer, err := svc.RWLayer.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit servicing container layer: %w", err)
+	}
+
+	img, err := daemon.imageService.GetImage(ctx, string(c.ImageID), backend.GetImageOpts{})
+	if err != nil {
+		return err
+	}
+
+	newImage := image.NewChildImage(img, image.ChildConfig{
+		ContainerConfig: c.Config,
+		DiffID:          newLayer.DiffID(),
+		Config:          c.Config,
+	}, img.OperatingSystem())
+
+	config, err := newImage.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode merged servicing image config: %w", err)
+	}
+
+	exportedImage, err := daemon.imageService.CreateImage(ctx, config, string(c.ImageID), newLayer.ContentStoreDigest())
+	if err != nil {
+		return fmt.Errorf("failed to create merged servicing image: %w", err)
+	}
+
+	c.ImageID = exportedImage.ImageID()
+	return daemon.containerStore.CommitContainer(c)
+}